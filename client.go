@@ -5,13 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"go/build"
-	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
-
-	"mime/multipart"
+	"time"
 
 	"github.com/gorilla/schema"
 	"github.com/omise/omise-go/internal"
@@ -31,15 +29,51 @@ type Client struct {
 	// Overrides
 	Endpoints map[internal.Endpoint]string
 
+	// RetryPolicy governs how Do, DoWithFormData and DoUploadDocument retry
+	// transient failures (retryable status codes and network errors). A nil
+	// RetryPolicy disables retries; use DefaultRetryPolicy for sensible
+	// defaults.
+	RetryPolicy *RetryPolicy
+
+	// Timeout bounds how long a DoContext-family call may take when the caller
+	// passes context.Background(). It has no effect on a context that already
+	// carries its own deadline.
+	Timeout time.Duration
+
+	// UploadSizeLimit caps, in bytes, how much file content DoUploadDocument will
+	// send; a larger file fails with ErrUploadTooLarge. Zero means unlimited.
+	UploadSizeLimit int64
+
 	// configuration
 	APIVersion string
 	GoVersion  string
 }
 
+// ClientOption customizes a Client at construction time via NewClient or
+// NewClientWithChainKey.
+type ClientOption func(*Client)
+
+// WithTransport overrides the base http.RoundTripper a Client sends requests through.
+// Apply it before any WithMiddleware options so the middleware chain wraps it.
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(c *Client) {
+		c.Client.Transport = rt
+	}
+}
+
+// WithMiddleware wraps the Client's current http.RoundTripper with mw. Options are
+// applied in the order passed to NewClient/NewClientWithChainKey, so the last
+// WithMiddleware given is the outermost layer a request passes through.
+func WithMiddleware(mw func(http.RoundTripper) http.RoundTripper) ClientOption {
+	return func(c *Client) {
+		c.Client.Transport = mw(c.Client.Transport)
+	}
+}
+
 // NewClient creates and returns a Client with the given public key and secret key.  Signs
 // in to http://omise.co and visit https://dashboard.omise.co/test/dashboard to obtain
 // your test (or live) keys.
-func NewClient(pkey, skey string) (*Client, error) {
+func NewClient(pkey, skey string, opts ...ClientOption) (*Client, error) {
 	switch {
 	case pkey == "" && skey == "":
 		return nil, ErrInvalidKey
@@ -62,10 +96,14 @@ func NewClient(pkey, skey string) (*Client, error) {
 		client.GoVersion = build.Default.ReleaseTags[len(build.Default.ReleaseTags)-1]
 	}
 
+	for _, opt := range opts {
+		opt(client)
+	}
+
 	return client, nil
 }
 
-func NewClientWithChainKey(ckey string) (*Client, error) {
+func NewClientWithChainKey(ckey string, opts ...ClientOption) (*Client, error) {
 	switch {
 	case ckey == "":
 		return nil, ErrInvalidKey
@@ -83,13 +121,30 @@ func NewClientWithChainKey(ckey string) (*Client, error) {
 	if len(build.Default.ReleaseTags) > 0 {
 		client.GoVersion = build.Default.ReleaseTags[len(build.Default.ReleaseTags)-1]
 	}
+
+	for _, opt := range opts {
+		opt(client)
+	}
+
 	return client, nil
 }
 
+// RequestOption customizes an *http.Request built by Client right before it is sent,
+// after headers and authentication have already been applied.
+type RequestOption func(*http.Request)
+
+// WithIdempotencyKey overrides the Omise-Idempotency-Key header that Client would
+// otherwise generate automatically for POST/PUT operations.
+func WithIdempotencyKey(key string) RequestOption {
+	return func(req *http.Request) {
+		req.Header.Set("Omise-Idempotency-Key", key)
+	}
+}
+
 // Request creates a new *http.Request that should performs the supplied Operation. Most
 // people should use the Do method instead.
 func (c *Client) Request(operation internal.Operation) (req *http.Request, err error) {
-	req, err = c.buildJSONRequest(operation)
+	req, _, err = c.buildJSONRequest(operation)
 	if err != nil {
 		return nil, err
 	}
@@ -102,12 +157,12 @@ func (c *Client) Request(operation internal.Operation) (req *http.Request, err e
 	return req, nil
 }
 
-func (c *Client) buildJSONRequest(operation internal.Operation) (*http.Request, error) {
+func (c *Client) buildJSONRequest(operation internal.Operation) (*http.Request, []byte, error) {
 	desc := operation.Describe()
 
 	b, err := json.Marshal(operation)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	body := bytes.NewReader(b)
@@ -117,7 +172,12 @@ func (c *Client) buildJSONRequest(operation internal.Operation) (*http.Request,
 		endpoint = ep
 	}
 
-	return http.NewRequest(desc.Method, endpoint+desc.Path, body)
+	req, err := http.NewRequest(desc.Method, endpoint+desc.Path, body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return req, b, nil
 }
 
 func (c *Client) setRequestHeaders(req *http.Request, desc *internal.Description) error {
@@ -154,33 +214,74 @@ func (c *Client) setRequestHeaders(req *http.Request, desc *internal.Description
 	return nil
 }
 
-// Do performs the supplied operation against Omise's REST API and unmarshal the response
-// into the given result parameter. Results are usually basic objects or a list that
-// corresponds to the operations being done.
-//
-// If the operation is successful, result should contains the response data. Otherwise a
-// non-nil error should be returned. Error maybe of the omise-go.Error struct type, in
-// which case you can further inspect the Code and Message field for more information.
-func (c *Client) Do(result interface{}, operation internal.Operation) error {
-	req, err := c.Request(operation)
-	if err != nil {
-		return err
+// ensureIdempotencyKey attaches a random Omise-Idempotency-Key header to POST/PUT
+// requests that do not already carry one, so retries performed by sendWithRetry are
+// safe to replay server-side.
+func (c *Client) ensureIdempotencyKey(req *http.Request) {
+	switch req.Method {
+	case http.MethodPost, http.MethodPut:
+		if req.Header.Get("Omise-Idempotency-Key") == "" {
+			req.Header.Set("Omise-Idempotency-Key", newIdempotencyKey())
+		}
 	}
+}
 
-	// response
-	resp, err := c.Client.Do(req)
-	if resp != nil {
-		defer resp.Body.Close()
-	}
-	if err != nil {
-		return err
-	}
+// sendWithRetry performs req, replaying bodyBytes onto it before every attempt, and
+// retries according to c.RetryPolicy when the response status or the transport error is
+// retryable. It returns the final response together with its fully-read body. When
+// bodyBytes is nil but req has a body (e.g. a streamed upload body that can only be read
+// once), retries are disabled regardless of c.RetryPolicy, since the request body cannot
+// be replayed.
+func (c *Client) sendWithRetry(req *http.Request, bodyBytes []byte) (*http.Response, []byte, error) {
+	policy := c.RetryPolicy
+	attempts := policy.maxAttempts()
+	if bodyBytes == nil && req.Body != nil {
+		attempts = 1
+	}
+
+	var (
+		resp    *http.Response
+		buffer  []byte
+		lastErr error
+	)
+
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if bodyBytes != nil {
+				req.Body = ioutil.NopCloser(bytes.NewReader(bodyBytes))
+			}
+			if err := sleepOrDone(req.Context(), policy.backoff(attempt-1, resp)); err != nil {
+				return nil, nil, err
+			}
+		}
 
-	buffer, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return &ErrTransport{err, buffer}
+		resp, lastErr = c.Client.Do(req)
+		if lastErr != nil {
+			if attempt < attempts-1 && isRetryableError(lastErr) {
+				continue
+			}
+			return nil, nil, lastErr
+		}
+
+		buffer, lastErr = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if lastErr != nil {
+			return resp, nil, &ErrTransport{lastErr, buffer}
+		}
+
+		if attempt < attempts-1 && policy.shouldRetryStatus(resp.StatusCode) {
+			continue
+		}
+
+		return resp, buffer, nil
 	}
 
+	return resp, buffer, lastErr
+}
+
+// unmarshalResult maps a completed response onto result, or returns the API error
+// carried in its body.
+func (c *Client) unmarshalResult(resp *http.Response, buffer []byte, result interface{}) error {
 	switch {
 	case resp.StatusCode != 200:
 		err := &Error{StatusCode: resp.StatusCode}
@@ -204,8 +305,43 @@ func (c *Client) Do(result interface{}, operation internal.Operation) error {
 	return nil
 }
 
+// Do performs the supplied operation against Omise's REST API and unmarshal the response
+// into the given result parameter. Results are usually basic objects or a list that
+// corresponds to the operations being done.
+//
+// If the operation is successful, result should contains the response data. Otherwise a
+// non-nil error should be returned. Error maybe of the omise-go.Error struct type, in
+// which case you can further inspect the Code and Message field for more information.
+//
+// Transient failures are retried according to c.RetryPolicy, and POST/PUT operations are
+// assigned a random Omise-Idempotency-Key unless opts overrides it with
+// WithIdempotencyKey.
+//
+// Deprecated: use DoContext, which binds the request to a context.Context so callers
+// can cancel it or apply a deadline.
+func (c *Client) Do(result interface{}, operation internal.Operation, opts ...RequestOption) error {
+	req, body, err := c.buildJSONRequest(operation)
+	if err != nil {
+		return err
+	}
+	if err := c.setRequestHeaders(req, operation.Describe()); err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(req)
+	}
+	c.ensureIdempotencyKey(req)
+	req = req.WithContext(withOperationName(req.Context(), operation))
+
+	resp, buffer, err := c.sendWithRetry(req, body)
+	if err != nil {
+		return err
+	}
+	return c.unmarshalResult(resp, buffer, result)
+}
+
 func (c *Client) FormDataRequest(operation internal.Operation) (req *http.Request, err error) {
-	req, err = c.buildFormDataRequest(operation)
+	req, _, err = c.buildFormDataRequest(operation)
 	if err != nil {
 		return nil, err
 	}
@@ -218,70 +354,60 @@ func (c *Client) FormDataRequest(operation internal.Operation) (req *http.Reques
 	return req, nil
 }
 
-func (c *Client) buildFormDataRequest(operation internal.Operation) (*http.Request, error) {
+func (c *Client) buildFormDataRequest(operation internal.Operation) (*http.Request, []byte, error) {
 	desc := operation.Describe()
 
 	form := url.Values{}
 	err := encoder.Encode(operation, form)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	body := strings.NewReader(form.Encode())
+	encoded := form.Encode()
+	body := strings.NewReader(encoded)
 
 	endpoint := string(desc.Endpoint)
 	if ep, ok := c.Endpoints[desc.Endpoint]; ok {
 		endpoint = ep
 	}
 
-	return http.NewRequest(desc.Method, endpoint+desc.Path, body)
+	req, err := http.NewRequest(desc.Method, endpoint+desc.Path, body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return req, []byte(encoded), nil
 }
 
-func (c *Client) DoWithFormData(result interface{}, operation internal.Operation) error {
-	req, err := c.FormDataRequest(operation)
+// DoWithFormData performs the supplied operation like Do, but encodes the operation as
+// form data instead of JSON. The same retry policy and idempotency key behavior as Do
+// apply.
+//
+// Deprecated: use DoWithFormDataContext, which binds the request to a context.Context
+// so callers can cancel it or apply a deadline.
+func (c *Client) DoWithFormData(result interface{}, operation internal.Operation, opts ...RequestOption) error {
+	req, body, err := c.buildFormDataRequest(operation)
 	if err != nil {
 		return err
 	}
-
-	// response
-	resp, err := c.Client.Do(req)
-	if resp != nil {
-		defer resp.Body.Close()
-	}
-	if err != nil {
+	if err := c.setRequestHeaders(req, operation.Describe()); err != nil {
 		return err
 	}
-
-	buffer, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return &ErrTransport{err, buffer}
+	for _, opt := range opts {
+		opt(req)
 	}
+	c.ensureIdempotencyKey(req)
+	req = req.WithContext(withOperationName(req.Context(), operation))
 
-	switch {
-	case resp.StatusCode != 200:
-		err := &Error{StatusCode: resp.StatusCode}
-		if err := json.Unmarshal(buffer, err); err != nil {
-			return &ErrTransport{err, buffer}
-		}
-
+	resp, buffer, err := c.sendWithRetry(req, body)
+	if err != nil {
 		return err
-	} // status == 200 && e == nil
-
-	if c.debug {
-		fmt.Println("resp:", resp.StatusCode, string(buffer))
-	}
-
-	if result != nil {
-		if err := json.Unmarshal(buffer, result); err != nil {
-			return &ErrTransport{err, buffer}
-		}
 	}
-
-	return nil
+	return c.unmarshalResult(resp, buffer, result)
 }
 
 func (c *Client) UploadDocumentRequest(operation internal.Operation) (req *http.Request, err error) {
-	req, err = c.buildUploadDocumentRequest(operation)
+	req, _, err = c.buildUploadDocumentRequest(operation)
 	if err != nil {
 		return nil, err
 	}
@@ -294,92 +420,31 @@ func (c *Client) UploadDocumentRequest(operation internal.Operation) (req *http.
 	return req, nil
 }
 
-func (c *Client) buildUploadDocumentRequest(operation internal.Operation) (*http.Request, error) {
-	desc := operation.Describe()
-
-	b, err := json.Marshal(operation)
-	if err != nil {
-		return nil, err
-	}
-
-	document := struct {
-		File     []byte
-		Filename string
-		Kind     string
-	}{}
-
-	if err := json.Unmarshal(b, &document); err != nil {
-		return nil, err
-	}
-
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-
-	if err := writer.WriteField("kind", document.Kind); err != nil {
-		return nil, err
-	}
-
-	part, err := writer.CreateFormFile("file", document.Filename)
-	if err != nil {
-		return nil, err
-	}
-
-	file := bytes.NewReader(document.File)
-
-	io.Copy(part, file)
-	writer.Close()
-
-	endpoint := string(desc.Endpoint)
-	if ep, ok := c.Endpoints[desc.Endpoint]; ok {
-		endpoint = ep
-	}
-
-	req, err := http.NewRequest(desc.Method, endpoint+desc.Path, body)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Add("Content-Type", writer.FormDataContentType())
-	return req, err
-}
-
-func (c *Client) DoUploadDocument(result interface{}, operation internal.Operation) error {
-	req, err := c.UploadDocumentRequest(operation)
+// DoUploadDocument performs the supplied operation like Do, but encodes the operation as
+// a multipart/form-data upload instead of JSON. The same retry policy and idempotency
+// key behavior as Do apply, except when the operation streams its payload (e.g.
+// UploadDocument.FileReader): a one-shot stream can't be safely resent, so that request
+// is never retried regardless of c.RetryPolicy.
+//
+// Deprecated: use DoUploadDocumentContext, which binds the request to a
+// context.Context so callers can cancel it or apply a deadline.
+func (c *Client) DoUploadDocument(result interface{}, operation internal.Operation, opts ...RequestOption) error {
+	req, body, err := c.buildUploadDocumentRequest(operation)
 	if err != nil {
 		return err
 	}
-
-	// response
-	resp, err := c.Client.Do(req)
-	if resp != nil {
-		defer resp.Body.Close()
-	}
-	if err != nil {
+	if err := c.setRequestHeaders(req, operation.Describe()); err != nil {
 		return err
 	}
-
-	buffer, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return &ErrTransport{err, buffer}
+	for _, opt := range opts {
+		opt(req)
 	}
+	c.ensureIdempotencyKey(req)
+	req = req.WithContext(withOperationName(req.Context(), operation))
 
-	switch {
-	case resp.StatusCode != 200:
-		err := &Error{StatusCode: resp.StatusCode}
-		if err := json.Unmarshal(buffer, err); err != nil {
-			return &ErrTransport{err, buffer}
-		}
-
+	resp, buffer, err := c.sendWithRetry(req, body)
+	if err != nil {
 		return err
-	} // status == 200 && e == nil
-
-	if c.debug {
-		fmt.Println("resp:", resp.StatusCode, string(buffer))
 	}
-
-	if result != nil {
-		if err := json.Unmarshal(buffer, result); err != nil {
-			return &ErrTransport{err, buffer}
-		}
-	}
-	return nil
+	return c.unmarshalResult(resp, buffer, result)
 }
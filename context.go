@@ -0,0 +1,99 @@
+package omise
+
+import (
+	"context"
+
+	"github.com/omise/omise-go/internal"
+)
+
+// boundContext derives the context used for a DoContext-family call. When ctx is
+// context.Background() and c.Timeout is set, it returns a context bounded by that
+// timeout; otherwise ctx is returned wrapped in a cancel func the caller must invoke.
+func (c *Client) boundContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if ctx == context.Background() && c.Timeout > 0 {
+		return context.WithTimeout(ctx, c.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// DoContext performs the supplied operation like Do, but binds the request to ctx so
+// that cancellation and deadlines propagate through http.Client.Do. If ctx is
+// context.Background(), it is derived into one bounded by c.Timeout when set.
+func (c *Client) DoContext(ctx context.Context, result interface{}, operation internal.Operation, opts ...RequestOption) error {
+	req, body, err := c.buildJSONRequest(operation)
+	if err != nil {
+		return err
+	}
+	if err := c.setRequestHeaders(req, operation.Describe()); err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(req)
+	}
+	c.ensureIdempotencyKey(req)
+
+	ctx, cancel := c.boundContext(ctx)
+	defer cancel()
+	req = req.WithContext(withOperationName(ctx, operation))
+
+	resp, buffer, err := c.sendWithRetry(req, body)
+	if err != nil {
+		return err
+	}
+	return c.unmarshalResult(resp, buffer, result)
+}
+
+// DoWithFormDataContext performs the supplied operation like DoWithFormData, but binds
+// the request to ctx as DoContext does.
+func (c *Client) DoWithFormDataContext(ctx context.Context, result interface{}, operation internal.Operation, opts ...RequestOption) error {
+	req, body, err := c.buildFormDataRequest(operation)
+	if err != nil {
+		return err
+	}
+	if err := c.setRequestHeaders(req, operation.Describe()); err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(req)
+	}
+	c.ensureIdempotencyKey(req)
+
+	ctx, cancel := c.boundContext(ctx)
+	defer cancel()
+	req = req.WithContext(withOperationName(ctx, operation))
+
+	resp, buffer, err := c.sendWithRetry(req, body)
+	if err != nil {
+		return err
+	}
+	return c.unmarshalResult(resp, buffer, result)
+}
+
+// DoUploadDocumentContext performs the supplied operation like DoUploadDocument, but
+// binds the request to ctx as DoContext does.
+func (c *Client) DoUploadDocumentContext(ctx context.Context, result interface{}, operation internal.Operation, opts ...RequestOption) error {
+	req, body, err := c.buildUploadDocumentRequest(operation)
+	if err != nil {
+		return err
+	}
+	if err := c.setRequestHeaders(req, operation.Describe()); err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(req)
+	}
+	c.ensureIdempotencyKey(req)
+
+	ctx, cancel := c.boundContext(ctx)
+	defer cancel()
+	req = req.WithContext(withOperationName(ctx, operation))
+
+	resp, buffer, err := c.sendWithRetry(req, body)
+	if err != nil {
+		return err
+	}
+	return c.unmarshalResult(resp, buffer, result)
+}
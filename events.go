@@ -0,0 +1,19 @@
+package omise
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event represents a webhook notification Omise sends when a resource changes state,
+// e.g. "charge.complete" or "transfer.create". Data holds the raw JSON of the resource
+// named by Key and should be unmarshaled into the matching type by the caller.
+type Event struct {
+	Object    string          `json:"object"`
+	ID        string          `json:"id"`
+	Livemode  bool            `json:"livemode"`
+	Location  string          `json:"location"`
+	Key       string          `json:"key"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
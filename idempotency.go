@@ -0,0 +1,20 @@
+package omise
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// newIdempotencyKey returns a random UUID v4 string suitable for use as the value of an
+// Omise-Idempotency-Key header.
+func newIdempotencyKey() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // variant 10
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
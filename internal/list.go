@@ -0,0 +1,10 @@
+package internal
+
+// ListOperation is implemented by list operations that page through results with
+// offset/limit parameters, letting Iterate (see the root package) advance
+// through multiple pages transparently.
+type ListOperation interface {
+	Operation
+	Page() (offset, limit int)
+	SetPage(offset, limit int)
+}
@@ -0,0 +1,17 @@
+package internal
+
+import "io"
+
+// StreamingUpload is implemented by upload operations that can supply their file
+// payload as an io.Reader, letting Client stream it straight into the multipart body
+// instead of buffering the whole file in memory. size is the file's length in bytes, or
+// -1 if it is not known up front.
+//
+// Streaming reports whether the operation is actually configured to stream its payload
+// right now. Operations backed by an in-memory []byte should return false so Client
+// falls back to its buffered, replayable multipart encoding instead — that path can be
+// retried, a one-shot io.Reader generally cannot.
+type StreamingUpload interface {
+	UploadFile() (file io.Reader, filename, kind string, size int64)
+	Streaming() bool
+}
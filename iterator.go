@@ -0,0 +1,150 @@
+package omise
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/omise/omise-go/internal"
+)
+
+// Iterator pages transparently through a ListOperation's results, prefetching the next
+// page in the background once the current page is half-consumed. At most one prefetch
+// is ever outstanding at a time.
+type Iterator[T any] struct {
+	client *Client
+	op     internal.ListOperation
+
+	items []T
+	idx   int
+
+	nextOffset int
+	limit      int
+	total      int
+	seen       bool
+	done       bool
+
+	mu      sync.Mutex
+	pending chan pageResult[T]
+
+	err error
+}
+
+type pageResult[T any] struct {
+	items []T
+	total int
+	err   error
+}
+
+// Iterate returns an Iterator that transparently pages through op's results, starting
+// from the offset and limit op is currently configured with. T must match the concrete
+// type each element of the list's data array decodes into.
+//
+// Iterate is a package-level function rather than a Client method because Go does not
+// allow a method to introduce a type parameter of its own.
+func Iterate[T any](c *Client, op internal.ListOperation) *Iterator[T] {
+	offset, limit := op.Page()
+	return &Iterator[T]{client: c, op: op, nextOffset: offset, limit: limit}
+}
+
+// fetch retrieves one page starting at offset and decodes its data array into []T.
+func (it *Iterator[T]) fetch(ctx context.Context, offset int) pageResult[T] {
+	it.op.SetPage(offset, it.limit)
+
+	list := &List{}
+	if err := it.client.DoContext(ctx, list, it.op); err != nil {
+		return pageResult[T]{err: err}
+	}
+
+	items := make([]T, len(list.Data))
+	for i, raw := range list.Data {
+		if err := json.Unmarshal(raw, &items[i]); err != nil {
+			return pageResult[T]{err: err}
+		}
+	}
+
+	return pageResult[T]{items: items, total: list.Total}
+}
+
+// startPrefetch kicks off a background fetch of the page starting at offset, unless one
+// is already outstanding.
+func (it *Iterator[T]) startPrefetch(ctx context.Context, offset int) {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	if it.pending != nil {
+		return
+	}
+
+	ch := make(chan pageResult[T], 1)
+	it.pending = ch
+	go func() {
+		ch <- it.fetch(ctx, offset)
+	}()
+}
+
+// takePrefetch waits for and clears the outstanding prefetch, falling back to a
+// synchronous fetch if none was started.
+func (it *Iterator[T]) takePrefetch(ctx context.Context, offset int) pageResult[T] {
+	it.mu.Lock()
+	ch := it.pending
+	it.pending = nil
+	it.mu.Unlock()
+
+	if ch == nil {
+		return it.fetch(ctx, offset)
+	}
+	return <-ch
+}
+
+// Next advances the iterator to the next item, fetching pages as needed, and reports
+// whether one is available. It returns false once the list is exhausted or a request
+// failed; use Err to tell the two apart.
+func (it *Iterator[T]) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+
+	if it.idx >= len(it.items) {
+		if it.seen && it.done {
+			return false
+		}
+
+		res := it.takePrefetch(ctx, it.nextOffset)
+		it.seen = true
+		if res.err != nil {
+			it.err = res.err
+			return false
+		}
+
+		it.items = res.items
+		it.idx = 0
+		it.total = res.total
+		it.nextOffset += len(res.items)
+		if len(res.items) == 0 || it.nextOffset >= res.total {
+			it.done = true
+		}
+
+		if len(it.items) == 0 {
+			return false
+		}
+	}
+
+	if !it.done && it.idx == len(it.items)/2 {
+		it.startPrefetch(ctx, it.nextOffset)
+	}
+
+	it.idx++
+	return true
+}
+
+// Value returns the item Next most recently advanced to.
+func (it *Iterator[T]) Value() T {
+	return it.items[it.idx-1]
+}
+
+// Err returns the error, if any, that caused Next to stop iterating early. It is nil if
+// the iterator simply ran out of items.
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
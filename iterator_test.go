@@ -0,0 +1,83 @@
+package omise
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/omise/omise-go/internal"
+	"github.com/omise/omise-go/operations"
+)
+
+type testDocument struct {
+	ID string `json:"id"`
+}
+
+// TestIterateListDocuments drives operations.ListDocuments through Iterate against a
+// fake server serving three pages of results, asserting every item is visited in order,
+// with no error, and without the caller ever touching Offset/Limit itself.
+func TestIterateListDocuments(t *testing.T) {
+	const total = 5
+	const limit = 2
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var page struct {
+			Offset int `json:"offset"`
+			Limit  int `json:"limit"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&page); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		end := page.Offset + page.Limit
+		if end > total {
+			end = total
+		}
+
+		data := make([]json.RawMessage, 0, end-page.Offset)
+		for i := page.Offset; i < end; i++ {
+			raw, _ := json.Marshal(testDocument{ID: idFor(i)})
+			data = append(data, raw)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(List{
+			Object: "list",
+			Offset: page.Offset,
+			Limit:  page.Limit,
+			Total:  total,
+			Data:   data,
+		})
+	}))
+	defer server.Close()
+
+	c := &Client{
+		Client:    server.Client(),
+		skey:      "skey_test",
+		Endpoints: map[internal.Endpoint]string{internal.APIStaging: server.URL},
+	}
+
+	it := Iterate[testDocument](c, &operations.ListDocuments{Limit: limit})
+
+	var got []string
+	ctx := context.Background()
+	for it.Next(ctx) {
+		got = append(got, it.Value().ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Iterate: %v", err)
+	}
+
+	want := []string{idFor(0), idFor(1), idFor(2), idFor(3), idFor(4)}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func idFor(i int) string {
+	return "doc_" + string(rune('a'+i))
+}
@@ -0,0 +1,20 @@
+package omise
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// List is the envelope Omise wraps around every list endpoint's response: paging
+// parameters alongside the raw items, which callers decode into whatever concrete type
+// the operation's results are.
+type List struct {
+	Object string            `json:"object"`
+	From   time.Time         `json:"from"`
+	To     time.Time         `json:"to"`
+	Offset int               `json:"offset"`
+	Limit  int               `json:"limit"`
+	Total  int               `json:"total"`
+	Order  string            `json:"order"`
+	Data   []json.RawMessage `json:"data"`
+}
@@ -0,0 +1,58 @@
+// Package omisemw provides http.RoundTripper middlewares for use with
+// omise.WithMiddleware: request/response logging, OpenTelemetry tracing and
+// Prometheus metrics.
+package omisemw
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"regexp"
+	"time"
+)
+
+var (
+	basicAuthPattern  = regexp.MustCompile(`(?i)(Authorization:\s*Basic\s+)\S+`)
+	cardNumberPattern = regexp.MustCompile(`\b(?:\d[ -]*?){13,19}\b`)
+)
+
+// Logger returns a middleware that writes a dump of each request and response to w,
+// redacting Basic-Auth credentials and card-number-shaped digit runs first.
+func Logger(w io.Writer) func(http.RoundTripper) http.RoundTripper {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return &loggingTransport{next: next, w: w}
+	}
+}
+
+type loggingTransport struct {
+	next http.RoundTripper
+	w    io.Writer
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if dump, err := httputil.DumpRequestOut(req, true); err == nil {
+		fmt.Fprintf(t.w, "--> %s\n", redact(dump))
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	elapsed := time.Since(start)
+
+	if err != nil {
+		fmt.Fprintf(t.w, "<-- %s %s: %v (%s)\n", req.Method, req.URL, err, elapsed)
+		return resp, err
+	}
+
+	if dump, derr := httputil.DumpResponse(resp, true); derr == nil {
+		fmt.Fprintf(t.w, "<-- %s (%s)\n", redact(dump), elapsed)
+	}
+
+	return resp, err
+}
+
+func redact(b []byte) []byte {
+	b = basicAuthPattern.ReplaceAll(b, []byte("${1}[redacted]"))
+	b = cardNumberPattern.ReplaceAll(b, []byte("[redacted]"))
+	return b
+}
@@ -0,0 +1,54 @@
+package omisemw
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	omise "github.com/omise/omise-go"
+)
+
+// OpenTelemetry returns a middleware that starts a span named "omise.request" around
+// every request, tagging it with http.method, omise.endpoint and omise.operation, and
+// recording the response status code (or transport error) on the span.
+func OpenTelemetry() func(http.RoundTripper) http.RoundTripper {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return &otelTransport{next: next, tracer: otel.Tracer("github.com/omise/omise-go")}
+	}
+}
+
+type otelTransport struct {
+	next   http.RoundTripper
+	tracer trace.Tracer
+}
+
+func (t *otelTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	operation, ok := omise.OperationFromContext(req.Context())
+	if !ok {
+		operation = req.URL.Path
+	}
+
+	ctx, span := t.tracer.Start(req.Context(), "omise.request", trace.WithAttributes(
+		attribute.String("http.method", req.Method),
+		attribute.String("omise.endpoint", req.URL.Host),
+		attribute.String("omise.operation", operation),
+	))
+	defer span.End()
+
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return resp, err
+	}
+
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,111 @@
+package omisemw
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	omise "github.com/omise/omise-go"
+)
+
+// Prometheus returns a middleware that registers and maintains request count,
+// duration and in-flight metrics on registry, all labeled by operation (the concrete
+// operation type Client was asked to perform, e.g. "*operations.ListDocuments").
+//
+// Building more than one Client against the same registry (or the same process-wide
+// default registry) is expected, so registration reuses whatever collectors are already
+// registered under these names instead of panicking on a duplicate.
+func Prometheus(registry prometheus.Registerer) func(http.RoundTripper) http.RoundTripper {
+	requests := registerCounterVec(registry, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "omise",
+		Name:      "requests_total",
+		Help:      "Total number of requests made to the Omise API, labeled by operation and status.",
+	}, []string{"operation", "status"}))
+
+	duration := registerHistogramVec(registry, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "omise",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of requests made to the Omise API, labeled by operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"}))
+
+	inFlight := registerGaugeVec(registry, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "omise",
+		Name:      "requests_in_flight",
+		Help:      "Number of requests currently in flight to the Omise API, labeled by operation.",
+	}, []string{"operation"}))
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return &prometheusTransport{next: next, requests: requests, duration: duration, inFlight: inFlight}
+	}
+}
+
+// registerCounterVec registers vec with registry, or returns the CounterVec already
+// registered under the same name if one exists.
+func registerCounterVec(registry prometheus.Registerer, vec *prometheus.CounterVec) *prometheus.CounterVec {
+	if err := registry.Register(vec); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			if existing, ok := are.ExistingCollector.(*prometheus.CounterVec); ok {
+				return existing
+			}
+		}
+	}
+	return vec
+}
+
+// registerHistogramVec registers vec with registry, or returns the HistogramVec already
+// registered under the same name if one exists.
+func registerHistogramVec(registry prometheus.Registerer, vec *prometheus.HistogramVec) *prometheus.HistogramVec {
+	if err := registry.Register(vec); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			if existing, ok := are.ExistingCollector.(*prometheus.HistogramVec); ok {
+				return existing
+			}
+		}
+	}
+	return vec
+}
+
+// registerGaugeVec registers vec with registry, or returns the GaugeVec already
+// registered under the same name if one exists.
+func registerGaugeVec(registry prometheus.Registerer, vec *prometheus.GaugeVec) *prometheus.GaugeVec {
+	if err := registry.Register(vec); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			if existing, ok := are.ExistingCollector.(*prometheus.GaugeVec); ok {
+				return existing
+			}
+		}
+	}
+	return vec
+}
+
+type prometheusTransport struct {
+	next     http.RoundTripper
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight *prometheus.GaugeVec
+}
+
+func (t *prometheusTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	operation, ok := omise.OperationFromContext(req.Context())
+	if !ok {
+		operation = req.URL.Path
+	}
+
+	t.inFlight.WithLabelValues(operation).Inc()
+	defer t.inFlight.WithLabelValues(operation).Dec()
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	t.duration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+
+	status := "error"
+	if resp != nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	t.requests.WithLabelValues(operation, status).Inc()
+
+	return resp, err
+}
@@ -0,0 +1,27 @@
+package omise
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/omise/omise-go/internal"
+)
+
+type operationContextKey int
+
+const operationNameContextKey operationContextKey = 0
+
+// withOperationName returns ctx carrying operation's concrete type name (e.g.
+// "*operations.ListDocuments"), so RoundTripper middleware such as omisemw can label
+// metrics and spans by operation without guessing it back from the request URL.
+func withOperationName(ctx context.Context, operation internal.Operation) context.Context {
+	return context.WithValue(ctx, operationNameContextKey, fmt.Sprintf("%T", operation))
+}
+
+// OperationFromContext returns the operation name Client attached to req's context, if
+// any. Middleware built on top of a Client's http.RoundTripper (see the omisemw
+// subpackage) can use this instead of req.URL.Path to label requests.
+func OperationFromContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(operationNameContextKey).(string)
+	return name, ok
+}
@@ -1,13 +1,20 @@
 package operations
 
 import (
+	"bytes"
+	"io"
+
 	"github.com/omise/omise-go/internal"
 )
 
+// UploadDocument uploads a KYC document to Omise. Set FileReader to stream the file
+// directly into the request body instead of buffering it in memory; when FileReader is
+// set it takes precedence over File, which is kept only for backward compatibility.
 type UploadDocument struct {
-	File     []byte
-	Filename string
-	Kind     string
+	File       []byte
+	FileReader io.Reader
+	Filename   string
+	Kind       string
 }
 
 func (req *UploadDocument) Describe() *internal.Description {
@@ -17,3 +24,56 @@ func (req *UploadDocument) Describe() *internal.Description {
 		Path:     "/documents",
 	}
 }
+
+// UploadFile implements internal.StreamingUpload.
+func (req *UploadDocument) UploadFile() (file io.Reader, filename, kind string, size int64) {
+	if req.FileReader != nil {
+		switch r := req.FileReader.(type) {
+		case interface{ Len() int }:
+			return req.FileReader, req.Filename, req.Kind, int64(r.Len())
+		case io.Seeker:
+			if cur, err := r.Seek(0, io.SeekCurrent); err == nil {
+				if end, err := r.Seek(0, io.SeekEnd); err == nil {
+					r.Seek(cur, io.SeekStart)
+					return req.FileReader, req.Filename, req.Kind, end - cur
+				}
+			}
+		}
+		return req.FileReader, req.Filename, req.Kind, -1
+	}
+
+	return bytes.NewReader(req.File), req.Filename, req.Kind, int64(len(req.File))
+}
+
+// Streaming implements internal.StreamingUpload. It reports true only when FileReader
+// is set: File is a plain []byte, so Client can always re-buffer it and retry safely,
+// but a caller-supplied FileReader is a one-shot stream that can't be replayed.
+func (req *UploadDocument) Streaming() bool {
+	return req.FileReader != nil
+}
+
+// ListDocuments retrieves the documents uploaded for the current account, paginated by
+// Offset and Limit.
+type ListDocuments struct {
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+func (req *ListDocuments) Describe() *internal.Description {
+	return &internal.Description{
+		Endpoint:    internal.APIStaging,
+		Method:      "GET",
+		Path:        "/documents",
+		ContentType: "application/json",
+	}
+}
+
+// Page and SetPage implement internal.ListOperation, so ListDocuments can be driven
+// through Iterate instead of paging by hand.
+func (req *ListDocuments) Page() (offset, limit int) {
+	return req.Offset, req.Limit
+}
+
+func (req *ListDocuments) SetPage(offset, limit int) {
+	req.Offset, req.Limit = offset, limit
+}
@@ -0,0 +1,129 @@
+package omise
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures how Client retries a request that failed for a transient
+// reason: a retryable HTTP status code (429, 502, 503, 504 by default) or a network
+// error. Retries use exponential backoff with full jitter, capped at MaxBackoff, and
+// honor a Retry-After header on the response when present.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts per request, including the
+	// first. Values less than 1 disable retries.
+	MaxAttempts int
+
+	// InitialBackoff is the base delay used to compute the backoff ceiling for
+	// the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the computed backoff ceiling.
+	MaxBackoff time.Duration
+
+	// RetryableStatusCodes lists the HTTP status codes that should be retried.
+	RetryableStatusCodes map[int]bool
+}
+
+// DefaultRetryPolicy returns a RetryPolicy with sensible defaults: up to 3 attempts,
+// exponential backoff with full jitter starting at 500ms and capped at 8s, retrying on
+// 429, 502, 503 and 504 responses.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     8 * time.Second,
+		RetryableStatusCodes: map[int]bool{
+			http.StatusTooManyRequests:    true,
+			http.StatusBadGateway:         true,
+			http.StatusServiceUnavailable: true,
+			http.StatusGatewayTimeout:     true,
+		},
+	}
+}
+
+// maxAttempts returns the effective attempt count, treating a nil policy (or one with
+// MaxAttempts < 1) as "no retries".
+func (p *RetryPolicy) maxAttempts() int {
+	if p == nil || p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// backoff returns the delay to sleep before the given zero-based retry attempt. When
+// resp carries a Retry-After header, that value takes precedence; otherwise it computes
+// an exponential ceiling from InitialBackoff and picks a random duration in [0, ceiling]
+// (full jitter).
+func (p *RetryPolicy) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if ra := resp.Header.Get("Retry-After"); ra != "" {
+			if secs, err := strconv.Atoi(ra); err == nil && secs >= 0 {
+				return time.Duration(secs) * time.Second
+			}
+		}
+	}
+
+	initial := p.InitialBackoff
+	if initial <= 0 {
+		initial = 500 * time.Millisecond
+	}
+	max := p.MaxBackoff
+	if max <= 0 {
+		max = 8 * time.Second
+	}
+
+	ceiling := time.Duration(math.Min(float64(max), float64(initial)*math.Pow(2, float64(attempt))))
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// shouldRetryStatus reports whether code is configured as retryable. A nil policy never
+// retries.
+func (p *RetryPolicy) shouldRetryStatus(code int) bool {
+	if p == nil {
+		return false
+	}
+	return p.RetryableStatusCodes[code]
+}
+
+// isRetryableError reports whether err represents a transient network failure worth
+// retrying. A *url.Error, which http.Client.Do wraps every failure in, is judged by the
+// error it wraps.
+func isRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if ue, ok := err.(*url.Error); ok {
+		err = ue.Err
+	}
+	_, ok := err.(net.Error)
+	return ok
+}
+
+// sleepOrDone waits for d, or returns ctx.Err() early if ctx is done first, so a
+// cancelled or expired context interrupts a pending retry backoff instead of blocking
+// for the full delay.
+func sleepOrDone(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,251 @@
+package omise
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyShouldRetryStatus(t *testing.T) {
+	policy := DefaultRetryPolicy()
+
+	for _, code := range []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout} {
+		if !policy.shouldRetryStatus(code) {
+			t.Errorf("shouldRetryStatus(%d) = false, want true", code)
+		}
+	}
+	for _, code := range []int{http.StatusOK, http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError} {
+		if policy.shouldRetryStatus(code) {
+			t.Errorf("shouldRetryStatus(%d) = true, want false", code)
+		}
+	}
+
+	var nilPolicy *RetryPolicy
+	if nilPolicy.shouldRetryStatus(http.StatusServiceUnavailable) {
+		t.Error("nil policy should never retry")
+	}
+}
+
+func TestRetryPolicyIsRetryableError(t *testing.T) {
+	if isRetryableError(nil) {
+		t.Error("isRetryableError(nil) = true, want false")
+	}
+
+	timeoutErr := &net.DNSError{IsTimeout: true}
+	if !isRetryableError(timeoutErr) {
+		t.Error("isRetryableError(net.Error) = false, want true")
+	}
+
+	if isRetryableError(errPlain{}) {
+		t.Error("isRetryableError(non-net error) = true, want false")
+	}
+}
+
+type errPlain struct{}
+
+func (errPlain) Error() string { return "plain error" }
+
+func TestRetryPolicyBackoffHonorsRetryAfter(t *testing.T) {
+	policy := DefaultRetryPolicy()
+
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"2"}}}
+	if got := policy.backoff(0, resp); got != 2*time.Second {
+		t.Fatalf("backoff with Retry-After: got %v, want 2s", got)
+	}
+}
+
+func TestRetryPolicyBackoffIsBounded(t *testing.T) {
+	policy := &RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 400 * time.Millisecond}
+
+	for attempt := 0; attempt < 5; attempt++ {
+		d := policy.backoff(attempt, nil)
+		if d < 0 || d > policy.MaxBackoff {
+			t.Fatalf("backoff(%d) = %v, want within [0, %v]", attempt, d, policy.MaxBackoff)
+		}
+	}
+}
+
+func TestMaxAttempts(t *testing.T) {
+	var nilPolicy *RetryPolicy
+	if got := nilPolicy.maxAttempts(); got != 1 {
+		t.Errorf("nil policy maxAttempts() = %d, want 1", got)
+	}
+
+	zero := &RetryPolicy{}
+	if got := zero.maxAttempts(); got != 1 {
+		t.Errorf("zero-value maxAttempts() = %d, want 1", got)
+	}
+
+	three := &RetryPolicy{MaxAttempts: 3}
+	if got := three.maxAttempts(); got != 3 {
+		t.Errorf("maxAttempts() = %d, want 3", got)
+	}
+}
+
+// TestSendWithRetryRetriesRetryableStatus drives sendWithRetry against a server that
+// fails with a retryable status twice before succeeding, asserting the final response
+// is the success and the body was replayed on every attempt.
+func TestSendWithRetryRetriesRetryableStatus(t *testing.T) {
+	var calls int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) != "payload" {
+			t.Errorf("request body = %q, want %q", body, "payload")
+		}
+
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	c := &Client{
+		Client: server.Client(),
+		RetryPolicy: &RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     2 * time.Millisecond,
+			RetryableStatusCodes: map[int]bool{
+				http.StatusServiceUnavailable: true,
+			},
+		},
+	}
+
+	req, err := http.NewRequest(http.MethodPost, server.URL, bytes.NewReader([]byte("payload")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, buffer, err := c.sendWithRetry(req, []byte("payload"))
+	if err != nil {
+		t.Fatalf("sendWithRetry: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("final status = %d, want 200", resp.StatusCode)
+	}
+	if string(buffer) != "ok" {
+		t.Fatalf("final body = %q, want %q", buffer, "ok")
+	}
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Fatalf("server was called %d times, want 3", got)
+	}
+}
+
+// TestSendWithRetryDoesNotRetryNonRetryableStatus asserts that a status code outside
+// RetryableStatusCodes is returned immediately without a second attempt.
+func TestSendWithRetryDoesNotRetryNonRetryableStatus(t *testing.T) {
+	var calls int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	c := &Client{Client: server.Client(), RetryPolicy: DefaultRetryPolicy()}
+
+	req, err := http.NewRequest(http.MethodPost, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, _, err := c.sendWithRetry(req, []byte{})
+	if err != nil {
+		t.Fatalf("sendWithRetry: %v", err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", resp.StatusCode)
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("server was called %d times, want 1 (no retry)", got)
+	}
+}
+
+// TestSendWithRetryAbortsOnContextCancellation asserts that a context cancelled while a
+// retry backoff is pending interrupts the wait instead of sleeping it out, which is the
+// behavior sleepOrDone exists to guarantee.
+func TestSendWithRetryAbortsOnContextCancellation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c := &Client{
+		Client: server.Client(),
+		RetryPolicy: &RetryPolicy{
+			MaxAttempts:    5,
+			InitialBackoff: time.Hour,
+			MaxBackoff:     time.Hour,
+			RetryableStatusCodes: map[int]bool{
+				http.StatusServiceUnavailable: true,
+			},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequest(http.MethodPost, server.URL, bytes.NewReader([]byte("payload")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = req.WithContext(ctx)
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := c.sendWithRetry(req, []byte("payload"))
+		done <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Fatalf("sendWithRetry error = %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("sendWithRetry did not return after context cancellation")
+	}
+}
+
+func TestEnsureIdempotencyKeyIsStableAcrossRetries(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "https://example.test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Client{}
+	c.ensureIdempotencyKey(req)
+	first := req.Header.Get("Omise-Idempotency-Key")
+	if first == "" {
+		t.Fatal("expected a non-empty idempotency key")
+	}
+
+	c.ensureIdempotencyKey(req)
+	if got := req.Header.Get("Omise-Idempotency-Key"); got != first {
+		t.Fatalf("idempotency key changed across calls: got %q, want %q", got, first)
+	}
+}
+
+func TestEnsureIdempotencyKeySkipsNonMutatingMethods(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://example.test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Client{}
+	c.ensureIdempotencyKey(req)
+	if got := req.Header.Get("Omise-Idempotency-Key"); got != "" {
+		t.Fatalf("expected no idempotency key on a GET request, got %q", got)
+	}
+}
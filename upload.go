@@ -0,0 +1,170 @@
+package omise
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/omise/omise-go/internal"
+)
+
+// ErrUploadTooLarge is returned by DoUploadDocument (and its context variant) when an
+// upload's file payload exceeds Client.UploadSizeLimit.
+var ErrUploadTooLarge = errors.New("omise: upload exceeds Client.UploadSizeLimit")
+
+// buildUploadDocumentRequest streams operation's file payload into a multipart body via
+// io.Pipe when operation implements internal.StreamingUpload and reports Streaming()
+// true, so the whole file never sits in memory at once. Everything else — including a
+// StreamingUpload backed by a plain []byte, which reports Streaming() false — falls
+// back to the buffered, replayable JSON-roundtrip encoding this package has always
+// used, so DoUploadDocument's retries have a body they can safely resend.
+func (c *Client) buildUploadDocumentRequest(operation internal.Operation) (*http.Request, []byte, error) {
+	uploader, ok := operation.(internal.StreamingUpload)
+	if !ok || !uploader.Streaming() {
+		return c.buildBufferedUploadDocumentRequest(operation)
+	}
+
+	desc := operation.Describe()
+	file, filename, kind, size := uploader.UploadFile()
+
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+
+	go func() {
+		if err := writer.WriteField("kind", kind); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+
+		part, err := writer.CreateFormFile("file", filename)
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+
+		reader := file
+		if c.UploadSizeLimit > 0 {
+			reader = io.LimitReader(file, c.UploadSizeLimit+1)
+		}
+
+		written, err := io.Copy(part, reader)
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		if c.UploadSizeLimit > 0 && written > c.UploadSizeLimit {
+			pw.CloseWithError(ErrUploadTooLarge)
+			return
+		}
+
+		if err := writer.Close(); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+
+		pw.Close()
+	}()
+
+	endpoint := string(desc.Endpoint)
+	if ep, ok := c.Endpoints[desc.Endpoint]; ok {
+		endpoint = ep
+	}
+
+	req, err := http.NewRequest(desc.Method, endpoint+desc.Path, pr)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+
+	if size >= 0 {
+		req.ContentLength = multipartEnvelopeSize(kind, filename) + size
+	}
+
+	// Streamed bodies can only be read once, so no cached bytes are returned to
+	// replay on retry.
+	return req, nil, nil
+}
+
+// multipartEnvelopeSize measures the number of bytes a multipart.Writer spends on
+// boundaries and headers around a "kind" field and a "file" part named filename, so
+// Content-Length can be set without buffering the file itself. Boundary strings are a
+// fixed length, so the measurement is accurate regardless of the boundary the real
+// writer picks.
+func multipartEnvelopeSize(kind, filename string) int64 {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	if err := w.WriteField("kind", kind); err != nil {
+		return 0
+	}
+	if _, err := w.CreateFormFile("file", filename); err != nil {
+		return 0
+	}
+	prefix := buf.Len()
+
+	suffixStart := buf.Len()
+	if err := w.Close(); err != nil {
+		return 0
+	}
+	suffix := buf.Len() - suffixStart
+
+	return int64(prefix + suffix)
+}
+
+// buildBufferedUploadDocumentRequest is the legacy path for operations that don't
+// stream their payload (they don't implement internal.StreamingUpload, or report
+// Streaming() false): it JSON-marshals the whole operation (including any File []byte)
+// and re-encodes it as multipart form data in memory.
+func (c *Client) buildBufferedUploadDocumentRequest(operation internal.Operation) (*http.Request, []byte, error) {
+	desc := operation.Describe()
+
+	b, err := json.Marshal(operation)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	document := struct {
+		File     []byte
+		Filename string
+		Kind     string
+	}{}
+
+	if err := json.Unmarshal(b, &document); err != nil {
+		return nil, nil, err
+	}
+	if c.UploadSizeLimit > 0 && int64(len(document.File)) > c.UploadSizeLimit {
+		return nil, nil, ErrUploadTooLarge
+	}
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	if err := writer.WriteField("kind", document.Kind); err != nil {
+		return nil, nil, err
+	}
+
+	part, err := writer.CreateFormFile("file", document.Filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	io.Copy(part, bytes.NewReader(document.File))
+	writer.Close()
+
+	bodyBytes := body.Bytes()
+
+	endpoint := string(desc.Endpoint)
+	if ep, ok := c.Endpoints[desc.Endpoint]; ok {
+		endpoint = ep
+	}
+
+	req, err := http.NewRequest(desc.Method, endpoint+desc.Path, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	return req, bodyBytes, nil
+}
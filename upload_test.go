@@ -0,0 +1,75 @@
+package omise
+
+import (
+	"io"
+	"runtime"
+	"testing"
+
+	"github.com/omise/omise-go/operations"
+)
+
+// zeroReader yields n zero bytes without ever allocating them up front, standing in for
+// a large file read from disk.
+type zeroReader struct {
+	remaining int64
+}
+
+func (r *zeroReader) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	for i := range p {
+		p[i] = 0
+	}
+	r.remaining -= int64(len(p))
+	return len(p), nil
+}
+
+// TestBuildUploadDocumentRequestStreamsLargeFiles uploads a 100 MB fake file through
+// UploadDocument.FileReader and asserts that draining the resulting request body does
+// not grow the heap anywhere near 100 MB, since buildUploadDocumentRequest streams it
+// through an io.Pipe instead of buffering it.
+func TestBuildUploadDocumentRequestStreamsLargeFiles(t *testing.T) {
+	const (
+		size      = 100 * 1024 * 1024
+		allowance = 10 * 1024 * 1024 // well under the file size
+	)
+
+	c := &Client{}
+	op := &operations.UploadDocument{
+		FileReader: &zeroReader{remaining: size},
+		Filename:   "large.pdf",
+		Kind:       "proof_of_identity",
+	}
+
+	req, body, err := c.buildUploadDocumentRequest(op)
+	if err != nil {
+		t.Fatalf("buildUploadDocumentRequest: %v", err)
+	}
+	if body != nil {
+		t.Fatalf("expected no cached replay body for a streamed upload, got %d bytes", len(body))
+	}
+
+	runtime.GC()
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	n, err := io.Copy(io.Discard, req.Body)
+	if err != nil {
+		t.Fatalf("reading streamed body: %v", err)
+	}
+	if n < size {
+		t.Fatalf("expected to read at least %d bytes, got %d", size, n)
+	}
+
+	runtime.GC()
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+
+	if grown := int64(after.HeapAlloc) - int64(before.HeapAlloc); grown > allowance {
+		t.Fatalf("heap grew by %d bytes streaming a %d byte upload, exceeding the %d byte allowance", grown, size, allowance)
+	}
+}
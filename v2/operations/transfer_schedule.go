@@ -10,13 +10,15 @@ import (
 //		ChargeID:    "chrg_456",
 //		Description: "updated charge.",
 //	}
-//	if e := client.Do(charge, update); e != nil {
+//	if e := client.DoContext(context.Background(), charge, update); e != nil {
 //		panic(e)
 //	}
 //
 //	fmt.Printf("updated charge: %#v\n", charge)
 //
 type ListSchedules struct {
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
 }
 
 func (req *ListSchedules) Describe() *internal.Description {
@@ -27,3 +29,12 @@ func (req *ListSchedules) Describe() *internal.Description {
 		ContentType: "application/json",
 	}
 }
+
+// Page and SetPage read and set ListSchedules' Offset and Limit paging parameters.
+func (req *ListSchedules) Page() (offset, limit int) {
+	return req.Offset, req.Limit
+}
+
+func (req *ListSchedules) SetPage(offset, limit int) {
+	req.Offset, req.Limit = offset, limit
+}
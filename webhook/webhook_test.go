@@ -0,0 +1,103 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("whsec_test")
+
+func sign(secret, timestamp, payload []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(timestamp)
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func TestVerifyBytesAcceptsFreshCorrectlySignedPayload(t *testing.T) {
+	payload := []byte(`{"object":"event","key":"charge.complete"}`)
+	timestamp := []byte(fmt.Sprintf("%d", time.Now().Unix()))
+	signature := []byte(hex.EncodeToString(sign(testSecret, timestamp, payload)))
+
+	event, err := VerifyBytes(testSecret, signature, timestamp, payload)
+	if err != nil {
+		t.Fatalf("VerifyBytes: %v", err)
+	}
+	if event.Key != "charge.complete" {
+		t.Fatalf("event.Key = %q, want %q", event.Key, "charge.complete")
+	}
+}
+
+func TestVerifyBytesRejectsBadSignature(t *testing.T) {
+	payload := []byte(`{"object":"event","key":"charge.complete"}`)
+	timestamp := []byte(fmt.Sprintf("%d", time.Now().Unix()))
+	signature := []byte(hex.EncodeToString(sign([]byte("wrong-secret"), timestamp, payload)))
+
+	_, err := VerifyBytes(testSecret, signature, timestamp, payload)
+	if err != ErrSignatureMismatch {
+		t.Fatalf("err = %v, want ErrSignatureMismatch", err)
+	}
+}
+
+func TestVerifyBytesRejectsTamperedPayload(t *testing.T) {
+	payload := []byte(`{"object":"event","key":"charge.complete"}`)
+	timestamp := []byte(fmt.Sprintf("%d", time.Now().Unix()))
+	signature := []byte(hex.EncodeToString(sign(testSecret, timestamp, payload)))
+
+	tampered := []byte(`{"object":"event","key":"charge.refund"}`)
+
+	_, err := VerifyBytes(testSecret, signature, timestamp, tampered)
+	if err != ErrSignatureMismatch {
+		t.Fatalf("err = %v, want ErrSignatureMismatch", err)
+	}
+}
+
+func TestVerifyBytesRejectsStaleTimestamp(t *testing.T) {
+	payload := []byte(`{"object":"event","key":"charge.complete"}`)
+	timestamp := []byte(fmt.Sprintf("%d", time.Now().Add(-time.Hour).Unix()))
+	signature := []byte(hex.EncodeToString(sign(testSecret, timestamp, payload)))
+
+	_, err := VerifyBytes(testSecret, signature, timestamp, payload)
+	if err != ErrReplay {
+		t.Fatalf("err = %v, want ErrReplay", err)
+	}
+}
+
+func TestVerifyBytesRejectsFutureTimestamp(t *testing.T) {
+	payload := []byte(`{"object":"event","key":"charge.complete"}`)
+	timestamp := []byte(fmt.Sprintf("%d", time.Now().Add(time.Hour).Unix()))
+	signature := []byte(hex.EncodeToString(sign(testSecret, timestamp, payload)))
+
+	_, err := VerifyBytes(testSecret, signature, timestamp, payload)
+	if err != ErrReplay {
+		t.Fatalf("err = %v, want ErrReplay", err)
+	}
+}
+
+func TestVerifyBytesHonorsCustomTolerance(t *testing.T) {
+	payload := []byte(`{"object":"event","key":"charge.complete"}`)
+	timestamp := []byte(fmt.Sprintf("%d", time.Now().Add(-time.Minute).Unix()))
+	signature := []byte(hex.EncodeToString(sign(testSecret, timestamp, payload)))
+
+	if _, err := VerifyBytes(testSecret, signature, timestamp, payload, WithTolerance(30*time.Second)); err != ErrReplay {
+		t.Fatalf("err = %v, want ErrReplay with a 30s tolerance", err)
+	}
+	if _, err := VerifyBytes(testSecret, signature, timestamp, payload, WithTolerance(5*time.Minute)); err != nil {
+		t.Fatalf("VerifyBytes with a 5m tolerance: %v", err)
+	}
+}
+
+func TestVerifyBytesRejectsInvalidTimestamp(t *testing.T) {
+	payload := []byte(`{"object":"event"}`)
+	timestamp := []byte("not-a-timestamp")
+	signature := []byte(hex.EncodeToString(sign(testSecret, timestamp, payload)))
+
+	if _, err := VerifyBytes(testSecret, signature, timestamp, payload); err == nil {
+		t.Fatal("expected an error for a non-numeric timestamp")
+	}
+}